@@ -1,7 +1,9 @@
 package batcher
 
 import (
+	"container/heap"
 	"context"
+	"errors"
 	"sync"
 	"time"
 
@@ -17,34 +19,294 @@ import (
 
 // Impl wraps an input with a batch policy.
 type Impl struct {
+	name  string
 	stats metrics.Type
 	log   log.Modular
 
-	child   input.Streamed
-	batcher *policy.Batcher
+	child    input.Streamed
+	batcher  *policy.Batcher
+	adaptive *adaptiveState
+
+	batcherFactory  func() *policy.Batcher
+	keyFn           KeyFn
+	maxInFlightKeys int
+	keyedBatches    map[string]*keyedBatch
+	keyedHeap       keyedBatchHeap
+
+	checkpoint Checkpointer
+
+	reload chan reloadRequest
+
+	deregister func()
 
 	messagesOut chan message.Transaction
 
 	shutSig *shutdown.Signaller
 }
 
-// New creates a new Batcher around an input.
-func New(batcher *policy.Batcher, child input.Streamed, log log.Modular, stats metrics.Type) input.Streamed {
+// ErrBatcherClosed is returned by ReloadPolicy when the batcher has begun
+// shutting down before the reload could be applied.
+var ErrBatcherClosed = errors.New("batcher is closed")
+
+// reloadRequest carries a new batch policy into loop, along with a channel
+// on which the outcome of applying it is reported back to the caller of
+// ReloadPolicy.
+type reloadRequest struct {
+	policy *policy.Batcher
+	result chan error
+}
+
+// New creates a new Batcher around an input, registered under name so that
+// out-of-band callers such as ReloadHandler can reach it (see Lookup and All).
+func New(name string, batcher *policy.Batcher, child input.Streamed, log log.Modular, stats metrics.Type) input.Streamed {
+	b := Impl{
+		name:        name,
+		stats:       stats,
+		log:         log,
+		child:       child,
+		batcher:     batcher,
+		reload:      make(chan reloadRequest),
+		messagesOut: make(chan message.Transaction),
+		shutSig:     shutdown.NewSignaller(),
+	}
+	b.deregister = Register(name, &b)
+	go b.loop()
+	return &b
+}
+
+// NewAdaptive creates a new Batcher around an input that self-tunes its
+// effective batch size against an ack latency SLO. The batcher starts at
+// minCount and grows towards maxCount while observed ack latency stays
+// comfortably under targetLatency, shrinking back towards minCount as
+// latency approaches it. It's registered under name so that out-of-band
+// callers such as ReloadHandler can reach it (see Lookup and All).
+func NewAdaptive(name string, batcher *policy.Batcher, child input.Streamed, log log.Modular, stats metrics.Type, targetLatency time.Duration, minCount, maxCount int) input.Streamed {
+	b := Impl{
+		name:    name,
+		stats:   stats,
+		log:     log,
+		child:   child,
+		batcher: batcher,
+		adaptive: &adaptiveState{
+			targetLatency:  targetLatency,
+			minCount:       minCount,
+			maxCount:       maxCount,
+			effectiveCount: minCount,
+		},
+		reload:      make(chan reloadRequest),
+		messagesOut: make(chan message.Transaction),
+		shutSig:     shutdown.NewSignaller(),
+	}
+	b.deregister = Register(name, &b)
+	go b.loop()
+	return &b
+}
+
+// KeyFn extracts a partition key from a message part. When supplied to
+// NewKeyed, messages are grouped into independent sub-batches per key so that
+// per-key ordering and batch composition is preserved across flushes.
+type KeyFn func(*message.Part) string
+
+// NewKeyed creates a new Batcher around an input that maintains an
+// independent policy.Batcher per key, as extracted by keyFn, instead of a
+// single shared batch. batcherFactory is called to construct a fresh
+// policy.Batcher for each newly observed key. maxInFlightKeys bounds the
+// number of keys with an open batch at any one time; once the cap is
+// reached, the least recently active key is force-flushed to make room. It's
+// registered under name like the other constructors, though ReloadPolicy
+// isn't supported for a keyed batcher.
+func NewKeyed(name string, batcherFactory func() *policy.Batcher, keyFn KeyFn, maxInFlightKeys int, child input.Streamed, log log.Modular, stats metrics.Type) input.Streamed {
+	b := Impl{
+		name:            name,
+		stats:           stats,
+		log:             log,
+		child:           child,
+		batcherFactory:  batcherFactory,
+		keyFn:           keyFn,
+		maxInFlightKeys: maxInFlightKeys,
+		keyedBatches:    map[string]*keyedBatch{},
+		messagesOut:     make(chan message.Transaction),
+		shutSig:         shutdown.NewSignaller(),
+	}
+	b.deregister = Register(name, &b)
+	go b.loop()
+	return &b
+}
+
+// NewCheckpointed creates a new Batcher around an input that persists
+// incoming parts and closed batch IDs via checkpoint, so that a crash
+// between a flushed batch being sent downstream and its ack resolving does
+// not silently lose the aggregated transactions. This matters for
+// at-least-once inputs (e.g. Kafka), where that loss otherwise shows up as
+// duplicates proportional to batch size on restart. On startup, any batch
+// that was closed but never marked flushed is replayed through
+// TransactionChan before regular processing begins. It's registered under
+// name so that out-of-band callers such as ReloadHandler can reach it (see
+// Lookup and All).
+func NewCheckpointed(name string, batcher *policy.Batcher, checkpoint Checkpointer, child input.Streamed, log log.Modular, stats metrics.Type) input.Streamed {
 	b := Impl{
+		name:        name,
 		stats:       stats,
 		log:         log,
 		child:       child,
 		batcher:     batcher,
+		checkpoint:  checkpoint,
+		reload:      make(chan reloadRequest),
 		messagesOut: make(chan message.Transaction),
 		shutSig:     shutdown.NewSignaller(),
 	}
+	b.deregister = Register(name, &b)
 	go b.loop()
 	return &b
 }
 
 //------------------------------------------------------------------------------
 
+// keyedBatch is a single key's sub-batch, tracked independently so that it
+// can be flushed (and acked) without disturbing any other key.
+type keyedBatch struct {
+	key          string
+	batcher      *policy.Batcher
+	pendingTrans []*transaction.Tracked
+	lastActivity time.Time
+	deadline     time.Time
+	heapIndex    int
+}
+
+// newSplitAck wraps ack so that it's only invoked once a transaction that
+// was split across n keyed groups has had every one of those groups
+// resolved, rather than once per group. The first non-nil error observed
+// across the groups is the one forwarded to ack.
+func newSplitAck(n int, ack func(context.Context, error) error) func(context.Context, error) error {
+	var mu sync.Mutex
+	remaining := n
+	var firstErr error
+
+	return func(ctx context.Context, err error) error {
+		mu.Lock()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		remaining--
+		done := remaining <= 0
+		outErr := firstErr
+		mu.Unlock()
+
+		if !done {
+			return nil
+		}
+		return ack(ctx, outErr)
+	}
+}
+
+// keyedBatchHeap orders keyed batches by their next flush deadline so the
+// loop can wait on a single timer for whichever key is due to flush next.
+type keyedBatchHeap []*keyedBatch
+
+func (h keyedBatchHeap) Len() int { return len(h) }
+
+func (h keyedBatchHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+
+func (h keyedBatchHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *keyedBatchHeap) Push(x interface{}) {
+	kb := x.(*keyedBatch)
+	kb.heapIndex = len(*h)
+	*h = append(*h, kb)
+}
+
+func (h *keyedBatchHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	kb := old[n-1]
+	old[n-1] = nil
+	kb.heapIndex = -1
+	*h = old[:n-1]
+	return kb
+}
+
+//------------------------------------------------------------------------------
+
+// adaptiveEWMAAlpha controls how quickly the latency EWMA reacts to new
+// samples; lower values smooth out noisy acks at the cost of slower reaction
+// to genuine load changes.
+const adaptiveEWMAAlpha = 0.2
+
+// adaptiveState tracks the self-tuning state for latency-driven batch sizing.
+// It is safe for concurrent use as it's read and written from both the batch
+// loop and the per-flush ack goroutines.
+type adaptiveState struct {
+	mu sync.Mutex
+
+	targetLatency time.Duration
+	minCount      int
+	maxCount      int
+
+	effectiveCount int
+	latencyEWMA    time.Duration
+	count          int
+}
+
+// add registers an added message part against the adaptive threshold and
+// returns true once that threshold has been reached, in which case the
+// internal counter is reset.
+func (a *adaptiveState) add() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.count++
+	if a.count >= a.effectiveCount {
+		a.count = 0
+		return true
+	}
+	return false
+}
+
+// observeLatency folds a fresh ack latency sample into the EWMA and adjusts
+// the effective batch size towards the configured SLO.
+func (a *adaptiveState) observeLatency(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.latencyEWMA == 0 {
+		a.latencyEWMA = d
+	} else {
+		a.latencyEWMA = time.Duration(adaptiveEWMAAlpha*float64(d) + (1-adaptiveEWMAAlpha)*float64(a.latencyEWMA))
+	}
+
+	switch {
+	case a.latencyEWMA < a.targetLatency/2:
+		a.effectiveCount += a.effectiveCount/4 + 1
+	case a.latencyEWMA >= a.targetLatency:
+		a.effectiveCount -= a.effectiveCount/4 + 1
+	}
+
+	if a.effectiveCount < a.minCount {
+		a.effectiveCount = a.minCount
+	}
+	if a.effectiveCount > a.maxCount {
+		a.effectiveCount = a.maxCount
+	}
+}
+
+// snapshot returns the current effective batch size and observed latency
+// EWMA for metrics reporting.
+func (a *adaptiveState) snapshot() (effectiveCount int, latencyEWMA time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.effectiveCount, a.latencyEWMA
+}
+
 func (m *Impl) loop() {
+	if m.keyFn != nil {
+		m.keyedLoop()
+		return
+	}
+
 	defer func() {
 		go func() {
 			select {
@@ -61,6 +323,16 @@ func (m *Impl) loop() {
 		m.batcher.CloseAsync()
 		_ = m.batcher.WaitForClose(shutdown.MaximumShutdownWait())
 
+		if m.checkpoint != nil {
+			// Runs after the final-flush defer below has already waited for
+			// pendingAcks (and therefore every MarkFlushed call) to
+			// resolve, since that defer was registered later and so unwinds
+			// first.
+			if err := m.checkpoint.Close(); err != nil {
+				m.log.Errorf("Failed to close batch checkpoint: %v\n", err)
+			}
+		}
+
 		close(m.messagesOut)
 		m.shutSig.ShutdownComplete()
 	}()
@@ -73,13 +345,73 @@ func (m *Impl) loop() {
 	pendingTrans := []*transaction.Tracked{}
 	pendingAcks := sync.WaitGroup{}
 
+	var adaptiveCountGauge, adaptiveLatencyGauge metrics.StatGauge
+	if m.adaptive != nil {
+		adaptiveCountGauge = m.stats.GetGauge("batcher.adaptive.count")
+		adaptiveLatencyGauge = m.stats.GetGauge("batcher.adaptive.latency_ns")
+	}
+
+	markFlushedFn := func(batchID string) {
+		if batchID == "" {
+			return
+		}
+		if err := m.checkpoint.MarkFlushed(batchID); err != nil {
+			m.log.Errorf("Failed to mark checkpointed batch as flushed: %v\n", err)
+		}
+	}
+
+	if m.checkpoint != nil {
+		recovered, err := m.checkpoint.Recover()
+		if err != nil {
+			m.log.Errorf("Failed to recover checkpointed batches: %v\n", err)
+		}
+		for _, pending := range recovered {
+			recoveredMsg := message.QuickBatch(nil)
+			for _, p := range pending.Parts {
+				recoveredMsg.Append(p)
+			}
+
+			resChan := make(chan error)
+			select {
+			case m.messagesOut <- message.NewTransaction(recoveredMsg, resChan):
+			case <-m.shutSig.CloseNowChan():
+				return
+			}
+
+			pendingAcks.Add(1)
+			go func(rChan <-chan error, batchID string) {
+				defer pendingAcks.Done()
+				select {
+				case <-m.shutSig.CloseNowChan():
+					return
+				case res, open := <-rChan:
+					if !open {
+						return
+					}
+					if res == nil {
+						markFlushedFn(batchID)
+					}
+				}
+			}(resChan, pending.ID)
+		}
+	}
+
 	flushBatchFn := func() {
 		sendMsg := m.batcher.Flush()
 		if sendMsg == nil {
 			return
 		}
 
+		var batchID string
+		if m.checkpoint != nil {
+			var err error
+			if batchID, err = m.checkpoint.CloseBatch(); err != nil {
+				m.log.Errorf("Failed to checkpoint batch: %v\n", err)
+			}
+		}
+
 		resChan := make(chan error)
+		sentAt := time.Now()
 		select {
 		case m.messagesOut <- message.NewTransaction(sendMsg, resChan):
 		case <-m.shutSig.CloseNowChan():
@@ -87,7 +419,7 @@ func (m *Impl) loop() {
 		}
 
 		pendingAcks.Add(1)
-		go func(rChan <-chan error, aggregatedTransactions []*transaction.Tracked) {
+		go func(rChan <-chan error, aggregatedTransactions []*transaction.Tracked, batchID string) {
 			defer pendingAcks.Done()
 
 			select {
@@ -97,6 +429,12 @@ func (m *Impl) loop() {
 				if !open {
 					return
 				}
+				if m.adaptive != nil {
+					m.adaptive.observeLatency(time.Since(sentAt))
+					effectiveCount, latencyEWMA := m.adaptive.snapshot()
+					adaptiveCountGauge.Set(int64(effectiveCount))
+					adaptiveLatencyGauge.Set(latencyEWMA.Nanoseconds())
+				}
 				closeNowCtx, done := m.shutSig.CloseNowCtx(context.Background())
 				for _, c := range aggregatedTransactions {
 					if err := c.Ack(closeNowCtx, res); err != nil {
@@ -104,9 +442,12 @@ func (m *Impl) loop() {
 						return
 					}
 				}
+				if res == nil {
+					markFlushedFn(batchID)
+				}
 				done()
 			}
-		}(resChan, pendingTrans)
+		}(resChan, pendingTrans, batchID)
 		pendingTrans = nil
 	}
 
@@ -146,7 +487,16 @@ func (m *Impl) loop() {
 
 			trackedTran := transaction.NewTracked(tran.Payload, tran.Ack)
 			_ = trackedTran.Message().Iter(func(i int, p *message.Part) error {
-				if m.batcher.Add(p) {
+				if m.checkpoint != nil {
+					if err := m.checkpoint.AppendPart(p); err != nil {
+						m.log.Errorf("Failed to checkpoint message part: %v\n", err)
+					}
+				}
+				triggered := m.batcher.Add(p)
+				if m.adaptive != nil && m.adaptive.add() {
+					triggered = true
+				}
+				if triggered {
 					flushBatch = true
 				}
 				return nil
@@ -155,6 +505,13 @@ func (m *Impl) loop() {
 		case <-nextTimedBatchChan:
 			flushBatch = true
 			nextTimedBatchChan = nil
+		case req := <-m.reload:
+			flushBatchFn()
+			m.batcher = req.policy
+			nextTimedBatchChan = nil
+			m.log.Infof("Reloaded batch policy.\n")
+			req.result <- nil
+			continue
 		case <-m.shutSig.CloseAtLeisureChan():
 			return
 		}
@@ -165,6 +522,265 @@ func (m *Impl) loop() {
 	}
 }
 
+// keyedLoop is the keyed-batching counterpart to loop, used when a KeyFn has
+// been configured. It maintains one policy.Batcher per observed key, flushing
+// and acking each independently, and uses keyedHeap to wait on a single timer
+// for whichever key's period elapses next.
+func (m *Impl) keyedLoop() {
+	defer func() {
+		go func() {
+			select {
+			case <-m.shutSig.CloseNowChan():
+				_ = m.child.WaitForClose(0)
+			case <-m.shutSig.HasClosedChan():
+			}
+		}()
+
+		m.child.CloseAsync()
+		_ = m.child.WaitForClose(shutdown.MaximumShutdownWait())
+
+		close(m.messagesOut)
+		m.shutSig.ShutdownComplete()
+	}()
+
+	pendingAcks := sync.WaitGroup{}
+
+	// flushKeyFn flushes key's currently accumulated sub-batch and sends it
+	// downstream, keeping the key's policy.Batcher (and its map entry) alive
+	// for subsequent messages. Its heap entry is removed, and is pushed again
+	// by updateDeadlineFn once a later message gives it a new deadline. This
+	// is the hot path, hit on every
+	// count/time trigger, so it must never block on anything other than
+	// messagesOut backpressure or shutdown.
+	flushKeyFn := func(key string) {
+		kb, ok := m.keyedBatches[key]
+		if !ok {
+			return
+		}
+		if kb.heapIndex >= 0 {
+			heap.Remove(&m.keyedHeap, kb.heapIndex)
+		}
+
+		sendMsg := kb.batcher.Flush()
+		pendingTrans := kb.pendingTrans
+		kb.pendingTrans = nil
+		if sendMsg == nil {
+			return
+		}
+
+		resChan := make(chan error)
+		select {
+		case m.messagesOut <- message.NewTransaction(sendMsg, resChan):
+		case <-m.shutSig.CloseNowChan():
+			return
+		}
+
+		pendingAcks.Add(1)
+		go func(rChan <-chan error, aggregatedTransactions []*transaction.Tracked) {
+			defer pendingAcks.Done()
+
+			select {
+			case <-m.shutSig.CloseNowChan():
+				return
+			case res, open := <-rChan:
+				if !open {
+					return
+				}
+				closeNowCtx, done := m.shutSig.CloseNowCtx(context.Background())
+				for _, c := range aggregatedTransactions {
+					if err := c.Ack(closeNowCtx, res); err != nil {
+						done()
+						return
+					}
+				}
+				done()
+			}
+		}(resChan, pendingTrans)
+	}
+
+	// closeKeyFn flushes key's remaining sub-batch and then permanently
+	// removes and closes its policy.Batcher. Closing a policy.Batcher blocks
+	// on WaitForClose, so this is reserved for eviction (infrequent, bounded
+	// by max_in_flight_keys) and final shutdown, never the per-flush hot
+	// path that flushKeyFn serves.
+	closeKeyFn := func(key string) {
+		flushKeyFn(key)
+
+		kb, ok := m.keyedBatches[key]
+		if !ok {
+			return
+		}
+		delete(m.keyedBatches, key)
+
+		kb.batcher.CloseAsync()
+		_ = kb.batcher.WaitForClose(shutdown.MaximumShutdownWait())
+	}
+
+	evictOldestFn := func() {
+		var oldestKey string
+		var oldestAt time.Time
+		for k, kb := range m.keyedBatches {
+			if oldestKey == "" || kb.lastActivity.Before(oldestAt) {
+				oldestKey = k
+				oldestAt = kb.lastActivity
+			}
+		}
+		if oldestKey != "" {
+			m.log.Debugln("Force-flushing least recently active keyed batch to respect max_in_flight_keys.")
+			closeKeyFn(oldestKey)
+		}
+	}
+
+	updateDeadlineFn := func(kb *keyedBatch) {
+		tNext := kb.batcher.UntilNext()
+		if tNext < 0 {
+			return
+		}
+		kb.deadline = time.Now().Add(tNext)
+		if kb.heapIndex >= 0 {
+			heap.Fix(&m.keyedHeap, kb.heapIndex)
+		} else {
+			heap.Push(&m.keyedHeap, kb)
+		}
+	}
+
+	defer func() {
+		// Flush and close every remaining key's sub-batcher concurrently
+		// rather than one at a time: with max_in_flight_keys potentially
+		// large, waiting on each in turn would let worst-case shutdown time
+		// scale with the number of open keys instead of staying bounded by
+		// a single shutdown.MaximumShutdownWait(), like the single-batcher
+		// loop's close of its one m.batcher.
+		m.log.Debugln("Flushing remaining keyed batches.")
+		var closeWG sync.WaitGroup
+		for key := range m.keyedBatches {
+			flushKeyFn(key)
+
+			kb := m.keyedBatches[key]
+			delete(m.keyedBatches, key)
+
+			kb.batcher.CloseAsync()
+			closeWG.Add(1)
+			go func(b *policy.Batcher) {
+				defer closeWG.Done()
+				go func() {
+					select {
+					case <-m.shutSig.CloseNowChan():
+						_ = b.WaitForClose(0)
+					case <-m.shutSig.HasClosedChan():
+					}
+				}()
+				_ = b.WaitForClose(shutdown.MaximumShutdownWait())
+			}(kb.batcher)
+		}
+		closeWG.Wait()
+
+		m.log.Debugln("Waiting for pending acks to resolve before shutting down.")
+		pendingAcks.Wait()
+		m.log.Debugln("Pending acks resolved.")
+	}()
+
+	for {
+		var nextTimedBatchChan <-chan time.Time
+		if m.keyedHeap.Len() > 0 {
+			if tNext := time.Until(m.keyedHeap[0].deadline); tNext > 0 {
+				nextTimedBatchChan = time.After(tNext)
+			} else {
+				nextTimedBatchChan = time.After(0)
+			}
+		}
+
+		select {
+		case tran, open := <-m.child.TransactionChan():
+			if !open {
+				return
+			}
+
+			byKey := map[string][]*message.Part{}
+			var order []string
+			_ = tran.Payload.Iter(func(i int, p *message.Part) error {
+				key := m.keyFn(p)
+				if _, exists := byKey[key]; !exists {
+					order = append(order, key)
+				}
+				byKey[key] = append(byKey[key], p)
+				return nil
+			})
+
+			// A transaction whose parts land in more than one key is split
+			// across that many independent key groups, each acked/flushed on
+			// its own schedule. splitAck ensures the original tran.Ack is
+			// still only invoked once, after every group it was split into
+			// has resolved, rather than once per group.
+			splitAck := newSplitAck(len(order), tran.Ack)
+
+			for _, key := range order {
+				kb, ok := m.keyedBatches[key]
+				if !ok {
+					if m.maxInFlightKeys > 0 && len(m.keyedBatches) >= m.maxInFlightKeys {
+						evictOldestFn()
+					}
+					kb = &keyedBatch{key: key, batcher: m.batcherFactory(), heapIndex: -1}
+					m.keyedBatches[key] = kb
+				}
+				kb.pendingTrans = append(kb.pendingTrans, transaction.NewTracked(tran.Payload, splitAck))
+				kb.lastActivity = time.Now()
+
+				var triggered bool
+				for _, p := range byKey[key] {
+					if kb.batcher.Add(p) {
+						triggered = true
+					}
+				}
+				if triggered {
+					flushKeyFn(key)
+				} else {
+					updateDeadlineFn(kb)
+				}
+			}
+		case <-nextTimedBatchChan:
+			if m.keyedHeap.Len() > 0 {
+				flushKeyFn(m.keyedHeap[0].key)
+			}
+		case <-m.shutSig.CloseAtLeisureChan():
+			return
+		}
+	}
+}
+
+// ReloadPolicy swaps the batcher's underlying batch policy without dropping
+// the batch currently being accumulated: the in-flight batch (and its
+// pendingTrans group) is flushed under the old policy first, then newPolicy
+// takes over for subsequent batches. It blocks until the reload has been
+// applied by the loop. Keyed batchers (NewKeyed) don't support reloading, as
+// each key owns its own policy instance constructed from a factory.
+func (m *Impl) ReloadPolicy(newPolicy *policy.Batcher) error {
+	if m.reload == nil {
+		return errors.New("batcher does not support policy reloads")
+	}
+
+	req := reloadRequest{policy: newPolicy, result: make(chan error, 1)}
+	select {
+	case m.reload <- req:
+	case <-m.shutSig.CloseAtLeisureChan():
+		return ErrBatcherClosed
+	case <-m.shutSig.HasClosedChan():
+		// loop can return on its own, e.g. when the child's transaction
+		// channel closes, without CloseAsync ever having been called. In
+		// that case CloseAtLeisureChan never fires, so HasClosedChan is the
+		// only signal that nothing will read m.reload again.
+		return ErrBatcherClosed
+	}
+	select {
+	case err := <-req.result:
+		return err
+	case <-m.shutSig.CloseAtLeisureChan():
+		return ErrBatcherClosed
+	case <-m.shutSig.HasClosedChan():
+		return ErrBatcherClosed
+	}
+}
+
 // Connected returns true if the underlying input is connected.
 func (m *Impl) Connected() bool {
 	return m.child.Connected()
@@ -178,6 +794,9 @@ func (m *Impl) TransactionChan() <-chan message.Transaction {
 
 // CloseAsync shuts down the Batcher and stops processing messages.
 func (m *Impl) CloseAsync() {
+	if m.deregister != nil {
+		m.deregister()
+	}
 	m.shutSig.CloseAtLeisure()
 }
 
@@ -0,0 +1,130 @@
+package batcher
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitAckFiresOnceAfterAllGroupsResolve(t *testing.T) {
+	var calls int
+	var gotErr error
+	ack := func(_ context.Context, err error) error {
+		calls++
+		gotErr = err
+		return nil
+	}
+
+	splitAck := newSplitAck(3, ack)
+
+	if err := splitAck(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error from intermediate ack: %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected 0 calls to the underlying ack after 1/3 groups, got %d", calls)
+	}
+
+	if err := splitAck(context.Background(), errors.New("boom")); err != nil {
+		t.Fatalf("unexpected error from intermediate ack: %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected 0 calls to the underlying ack after 2/3 groups, got %d", calls)
+	}
+
+	if err := splitAck(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error from final ack: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected exactly 1 call to the underlying ack after 3/3 groups, got %d", calls)
+	}
+	if gotErr == nil || gotErr.Error() != "boom" {
+		t.Fatalf("expected the first non-nil error to be forwarded, got %v", gotErr)
+	}
+}
+
+func TestSplitAckConcurrentGroups(t *testing.T) {
+	const n = 50
+
+	var calls int
+	ack := func(_ context.Context, _ error) error {
+		calls++
+		return nil
+	}
+	splitAck := newSplitAck(n, ack)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = splitAck(context.Background(), nil)
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("expected exactly 1 call to the underlying ack across %d concurrent groups, got %d", n, calls)
+	}
+}
+
+func TestAdaptiveStateAddTriggersAtEffectiveCount(t *testing.T) {
+	a := &adaptiveState{minCount: 2, maxCount: 10, effectiveCount: 3}
+
+	if a.add() {
+		t.Fatalf("expected add() to return false before effectiveCount is reached")
+	}
+	if a.add() {
+		t.Fatalf("expected add() to return false before effectiveCount is reached")
+	}
+	if !a.add() {
+		t.Fatalf("expected add() to return true once effectiveCount is reached")
+	}
+
+	count, _ := a.snapshot()
+	if count != 3 {
+		t.Fatalf("expected effectiveCount to be unchanged by add(), got %d", count)
+	}
+}
+
+func TestAdaptiveStateObserveLatencyGrowsAndShrinks(t *testing.T) {
+	a := &adaptiveState{targetLatency: 100 * time.Millisecond, minCount: 2, maxCount: 100, effectiveCount: 10}
+
+	a.observeLatency(10 * time.Millisecond)
+	count, _ := a.snapshot()
+	if count <= 10 {
+		t.Fatalf("expected effectiveCount to grow when latency is well under target, got %d", count)
+	}
+
+	a.observeLatency(1 * time.Second)
+	count, _ = a.snapshot()
+	if count >= 10 {
+		t.Fatalf("expected effectiveCount to shrink back once latency exceeds target, got %d", count)
+	}
+	if count < a.minCount {
+		t.Fatalf("expected effectiveCount to never drop below minCount, got %d", count)
+	}
+}
+
+func TestKeyedBatchHeapOrdersByDeadline(t *testing.T) {
+	now := time.Now()
+	h := &keyedBatchHeap{}
+
+	heap.Push(h, &keyedBatch{key: "c", deadline: now.Add(3 * time.Second), heapIndex: -1})
+	heap.Push(h, &keyedBatch{key: "a", deadline: now.Add(1 * time.Second), heapIndex: -1})
+	heap.Push(h, &keyedBatch{key: "b", deadline: now.Add(2 * time.Second), heapIndex: -1})
+
+	var order []string
+	for h.Len() > 0 {
+		order = append(order, heap.Pop(h).(*keyedBatch).key)
+	}
+
+	want := []string{"a", "b", "c"}
+	for i, k := range want {
+		if order[i] != k {
+			t.Fatalf("expected pop order %v, got %v", want, order)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package batcher
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func newTestPart(content string) *message.Part {
+	return message.NewPart([]byte(content))
+}
+
+func TestBoltCheckpointerAppendCloseRecoverRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+
+	cp, err := NewBoltCheckpointer(path)
+	if err != nil {
+		t.Fatalf("failed to open checkpointer: %v", err)
+	}
+
+	for _, v := range []string{"foo", "bar"} {
+		if err := cp.AppendPart(newTestPart(v)); err != nil {
+			t.Fatalf("failed to append part: %v", err)
+		}
+	}
+
+	batchID, err := cp.CloseBatch()
+	if err != nil {
+		t.Fatalf("failed to close batch: %v", err)
+	}
+	if batchID == "" {
+		t.Fatalf("expected a non-empty batch ID from closing a non-empty batch")
+	}
+
+	if err := cp.AppendPart(newTestPart("baz")); err != nil {
+		t.Fatalf("failed to append part: %v", err)
+	}
+
+	recovered, err := cp.Recover()
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if len(recovered) != 2 {
+		t.Fatalf("expected 2 pending batches recovered (the closed one plus the sealed open one), got %d", len(recovered))
+	}
+	if recovered[0].ID != batchID {
+		t.Fatalf("expected the first recovered batch to be the one closed before restart, got %q want %q", recovered[0].ID, batchID)
+	}
+	if len(recovered[0].Parts) != 2 {
+		t.Fatalf("expected 2 parts in the closed batch, got %d", len(recovered[0].Parts))
+	}
+	if len(recovered[1].Parts) != 1 || string(recovered[1].Parts[0].Get()) != "baz" {
+		t.Fatalf("expected the sealed open batch to contain the mid-accumulation part, got %+v", recovered[1])
+	}
+
+	if err := cp.MarkFlushed(recovered[0].ID); err != nil {
+		t.Fatalf("failed to mark flushed: %v", err)
+	}
+	if err := cp.MarkFlushed(recovered[1].ID); err != nil {
+		t.Fatalf("failed to mark flushed: %v", err)
+	}
+
+	recovered, err = cp.Recover()
+	if err != nil {
+		t.Fatalf("failed to recover after marking flushed: %v", err)
+	}
+	if len(recovered) != 0 {
+		t.Fatalf("expected no pending batches once every recovered batch has been marked flushed, got %d", len(recovered))
+	}
+
+	if err := cp.Close(); err != nil {
+		t.Fatalf("failed to close checkpointer: %v", err)
+	}
+}
+
+func TestBoltCheckpointerRecoverSealsOpenPartsOnlyOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+
+	cp, err := NewBoltCheckpointer(path)
+	if err != nil {
+		t.Fatalf("failed to open checkpointer: %v", err)
+	}
+	defer cp.Close()
+
+	if err := cp.AppendPart(newTestPart("only-part")); err != nil {
+		t.Fatalf("failed to append part: %v", err)
+	}
+
+	first, err := cp.Recover()
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if len(first) != 1 {
+		t.Fatalf("expected 1 pending batch sealed from open_parts, got %d", len(first))
+	}
+
+	second, err := cp.Recover()
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if len(second) != 1 {
+		t.Fatalf("expected the same still-unflushed batch to recover again, not be duplicated, got %d", len(second))
+	}
+	if second[0].ID != first[0].ID {
+		t.Fatalf("expected recovering twice without marking flushed to return the same batch ID, got %q then %q", first[0].ID, second[0].ID)
+	}
+}
+
+func TestBoltCheckpointerRecoverOrdersMoreThanNineBatchesNumerically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+
+	cp, err := NewBoltCheckpointer(path)
+	if err != nil {
+		t.Fatalf("failed to open checkpointer: %v", err)
+	}
+	defer cp.Close()
+
+	const n = 11
+	var wantIDs []string
+	for i := 0; i < n; i++ {
+		if err := cp.AppendPart(newTestPart("part")); err != nil {
+			t.Fatalf("failed to append part: %v", err)
+		}
+		batchID, err := cp.CloseBatch()
+		if err != nil {
+			t.Fatalf("failed to close batch: %v", err)
+		}
+		wantIDs = append(wantIDs, batchID)
+	}
+
+	recovered, err := cp.Recover()
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if len(recovered) != n {
+		t.Fatalf("expected %d pending batches, got %d", n, len(recovered))
+	}
+
+	var gotIDs []string
+	for _, b := range recovered {
+		gotIDs = append(gotIDs, b.ID)
+	}
+	for i := range wantIDs {
+		if gotIDs[i] != wantIDs[i] {
+			t.Fatalf("expected batches recovered in closed order once more than 9 are pending, got %v want %v", gotIDs, wantIDs)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package batcher
+
+import "sync"
+
+// registry tracks every live Impl by name (keyed ones included, although
+// their ReloadPolicy returns an error), so that out-of-band callers such as
+// ReloadHandler can reach them and call ReloadPolicy without threading a
+// reference through the pipeline construction code.
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Impl{}
+)
+
+// Register makes m reachable by name via Lookup. It returns a deregister
+// function the caller should invoke once m is no longer live (typically from
+// its CloseAsync). Registering under a name already in use replaces the
+// previous entry rather than erroring, since component names are reused
+// across pipeline reconfigurations.
+func Register(name string, m *Impl) (deregister func()) {
+	registryMu.Lock()
+	registry[name] = m
+	registryMu.Unlock()
+
+	return func() {
+		registryMu.Lock()
+		if registry[name] == m {
+			delete(registry, name)
+		}
+		registryMu.Unlock()
+	}
+}
+
+// Lookup returns the registered Impl for name, if any is currently live.
+func Lookup(name string) (*Impl, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	m, ok := registry[name]
+	return m, ok
+}
+
+// All returns a snapshot of every currently registered Impl by name, for
+// callers such as ReloadHandler that apply an update to every active
+// batcher rather than a single named one.
+func All() map[string]*Impl {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	out := make(map[string]*Impl, len(registry))
+	for name, m := range registry {
+		out[name] = m
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package batcher
+
+import "testing"
+
+func TestRegisterLookupAllDeregister(t *testing.T) {
+	a := &Impl{name: "a"}
+	b := &Impl{name: "b"}
+
+	deregisterA := Register("a", a)
+	deregisterB := Register("b", b)
+
+	got, ok := Lookup("a")
+	if !ok || got != a {
+		t.Fatalf("expected to look up the registered instance for 'a', got %v ok=%v", got, ok)
+	}
+
+	all := All()
+	if len(all) != 2 || all["a"] != a || all["b"] != b {
+		t.Fatalf("expected All() to return both registered instances, got %v", all)
+	}
+
+	deregisterA()
+
+	if _, ok := Lookup("a"); ok {
+		t.Fatalf("expected 'a' to no longer be looked up after deregistering")
+	}
+	if _, ok := Lookup("b"); !ok {
+		t.Fatalf("expected 'b' to remain registered after only 'a' was deregistered")
+	}
+
+	deregisterB()
+	if len(All()) != 0 {
+		t.Fatalf("expected no instances left registered, got %v", All())
+	}
+}
+
+func TestRegisterUnderReusedNameReplacesPreviousEntry(t *testing.T) {
+	first := &Impl{name: "reused"}
+	second := &Impl{name: "reused"}
+
+	deregisterFirst := Register("reused", first)
+	deregisterSecond := Register("reused", second)
+
+	got, ok := Lookup("reused")
+	if !ok || got != second {
+		t.Fatalf("expected the most recently registered instance to win, got %v", got)
+	}
+
+	// The first instance's deregister func should be a no-op now that its
+	// name has been taken over by a newer registration, since calling it
+	// must not evict the second instance.
+	deregisterFirst()
+	if _, ok := Lookup("reused"); !ok {
+		t.Fatalf("expected the second instance to remain registered after the first's deregister func ran")
+	}
+
+	deregisterSecond()
+	if _, ok := Lookup("reused"); ok {
+		t.Fatalf("expected 'reused' to be gone after deregistering the instance that actually owns it")
+	}
+}
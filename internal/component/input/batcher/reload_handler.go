@@ -0,0 +1,58 @@
+package batcher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/benthosdev/benthos/v4/internal/batch/policy"
+)
+
+// PolicyFromYAML parses a batch policy config snippet into a fresh
+// *policy.Batcher. It's injected into ReloadHandler rather than called
+// directly so that this package doesn't need to depend on the config
+// parsing/field package just to serve reloads; a fresh *policy.Batcher must
+// be built per registered instance since a policy.Batcher isn't safe to
+// share across Impls.
+type PolicyFromYAML func(yamlConfig []byte) (*policy.Batcher, error)
+
+// ReloadHandler returns an http.Handler for the admin API's
+// /batcher/reload endpoint. A POST body containing a batch policy config
+// (in the same YAML form accepted by the rest of the config) is parsed
+// once per registered instance via parsePolicy and applied with
+// ReloadPolicy to every batcher currently registered via Register,
+// regardless of name. Instances for which the reload fails (e.g. a keyed
+// batcher, which doesn't support it) are reported in the response but don't
+// prevent the reload from being attempted against the rest.
+func ReloadHandler(parsePolicy PolicyFromYAML) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		var failed []string
+		for name, m := range All() {
+			newPolicy, err := parsePolicy(body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to parse batch policy: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := m.ReloadPolicy(newPolicy); err != nil {
+				failed = append(failed, fmt.Sprintf("%v: %v", name, err))
+			}
+		}
+
+		if len(failed) > 0 {
+			http.Error(w, fmt.Sprintf("failed to reload %d batcher(s): %v", len(failed), failed), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,89 @@
+package batcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/benthosdev/benthos/v4/internal/batch/policy"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+)
+
+// newTestRegisteredImpl registers an Impl with a reload channel serviced by
+// a background goroutine that applies whatever ReloadHandler sends it,
+// standing in for loop() without needing a real policy.Batcher wired
+// through an actual input pipeline.
+func newTestRegisteredImpl(t *testing.T, name string) (m *Impl, deregister func()) {
+	t.Helper()
+	m = &Impl{
+		name:    name,
+		reload:  make(chan reloadRequest),
+		shutSig: shutdown.NewSignaller(),
+	}
+	go func() {
+		for req := range m.reload {
+			req.result <- nil
+		}
+	}()
+	return m, Register(name, m)
+}
+
+func TestReloadHandlerAppliesToEveryRegisteredInstance(t *testing.T) {
+	m1, deregister1 := newTestRegisteredImpl(t, "reload-handler-test-1")
+	defer deregister1()
+	m2, deregister2 := newTestRegisteredImpl(t, "reload-handler-test-2")
+	defer deregister2()
+	_ = m1
+	_ = m2
+
+	var calls int
+	parsePolicy := func(yamlConfig []byte) (*policy.Batcher, error) {
+		calls++
+		return nil, nil
+	}
+
+	handler := ReloadHandler(parsePolicy)
+
+	req := httptest.NewRequest(http.MethodPost, "/batcher/reload", strings.NewReader("count: 10\n"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if calls != 2 {
+		t.Fatalf("expected parsePolicy to be called once per registered instance, got %d calls", calls)
+	}
+}
+
+func TestReloadHandlerRejectsNonPost(t *testing.T) {
+	handler := ReloadHandler(func([]byte) (*policy.Batcher, error) { return nil, nil })
+
+	req := httptest.NewRequest(http.MethodGet, "/batcher/reload", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for a non-POST request, got %d", rec.Code)
+	}
+}
+
+func TestReloadHandlerReportsInstanceFailures(t *testing.T) {
+	m := &Impl{
+		name:    "reload-handler-test-unsupported",
+		shutSig: shutdown.NewSignaller(),
+	}
+	deregister := Register(m.name, m)
+	defer deregister()
+
+	handler := ReloadHandler(func([]byte) (*policy.Batcher, error) { return nil, nil })
+
+	req := httptest.NewRequest(http.MethodPost, "/batcher/reload", strings.NewReader("count: 10\n"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected a failure to be reported when an instance doesn't support reload, got %d", rec.Code)
+	}
+}
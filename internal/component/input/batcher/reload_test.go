@@ -0,0 +1,64 @@
+package batcher
+
+import (
+	"testing"
+
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+)
+
+func TestReloadPolicyAppliesViaReloadChan(t *testing.T) {
+	m := &Impl{
+		reload:  make(chan reloadRequest),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		req := <-m.reload
+		req.result <- nil
+	}()
+	go func() {
+		done <- m.ReloadPolicy(nil)
+	}()
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected ReloadPolicy to succeed once the loop consumes it, got %v", err)
+	}
+}
+
+func TestReloadPolicyUnsupportedWithoutReloadChan(t *testing.T) {
+	m := &Impl{shutSig: shutdown.NewSignaller()}
+
+	if err := m.ReloadPolicy(nil); err == nil {
+		t.Fatalf("expected an error reloading a batcher with no reload channel (e.g. a keyed batcher)")
+	}
+}
+
+func TestReloadPolicyFailsOnceCloseAtLeisureHasFired(t *testing.T) {
+	m := &Impl{
+		reload:  make(chan reloadRequest),
+		shutSig: shutdown.NewSignaller(),
+	}
+	m.shutSig.CloseAtLeisure()
+
+	if err := m.ReloadPolicy(nil); err != ErrBatcherClosed {
+		t.Fatalf("expected ErrBatcherClosed once CloseAtLeisure has fired, got %v", err)
+	}
+}
+
+// TestReloadPolicyFailsOnceLoopHasExitedNaturally covers the case where loop
+// returns because the child's transaction channel closed, without
+// CloseAsync ever having been called: CloseAtLeisureChan never fires, so
+// ReloadPolicy must also watch HasClosedChan or it blocks forever sending
+// to a reload channel nothing will ever read again.
+func TestReloadPolicyFailsOnceLoopHasExitedNaturally(t *testing.T) {
+	m := &Impl{
+		reload:  make(chan reloadRequest),
+		shutSig: shutdown.NewSignaller(),
+	}
+	m.shutSig.ShutdownComplete()
+
+	if err := m.ReloadPolicy(nil); err != ErrBatcherClosed {
+		t.Fatalf("expected ErrBatcherClosed once the loop has exited, got %v", err)
+	}
+}
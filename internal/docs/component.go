@@ -2,6 +2,7 @@ package docs
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -434,3 +435,193 @@ func (c *ComponentSpec) AsMarkdown(nest bool, fullConfigExample interface{}) ([]
 
 	return buf.Bytes(), err
 }
+
+// jsonSchema is a (subset of a) Draft-07 JSON Schema document, used by
+// ComponentSpec.AsJSONSchema to describe a component's configuration fields.
+type jsonSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Default     interface{}            `json:"default,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+	Examples    []interface{}          `json:"examples,omitempty"`
+	Properties  map[string]*jsonSchema `json:"properties,omitempty"`
+	Items       *jsonSchema            `json:"items,omitempty"`
+
+	// BenthosInterpolation reflects FieldSpec.Interpolation so that editors
+	// can flag fields supporting interpolation functions without parsing the
+	// rendered markdown docs.
+	BenthosInterpolation string `json:"x-benthos-interpolation,omitempty"`
+}
+
+// AsJSONSchema renders the Fields of a component, along with a full
+// configuration example, into a Draft-07 JSON Schema document. It infers
+// field types the same way as AsMarkdown (mapped onto JSON Schema type
+// names), and additionally renders Options as enum and nested Children as
+// nested properties rather than dotted field names, so that editors such as VS
+// Code and IntelliJ can offer completion and validation on Benthos config
+// files without a bespoke language server.
+func (c *ComponentSpec) AsJSONSchema(fullConfigExample interface{}) ([]byte, error) {
+	if tmpBytes, err := yaml.Marshal(fullConfigExample); err == nil {
+		fullConfigExample = map[string]interface{}{}
+		if err = yaml.Unmarshal(tmpBytes, &fullConfigExample); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	gConf := gabs.Wrap(fullConfigExample)
+
+	root := &jsonSchema{
+		Schema:      "http://json-schema.org/draft-07/schema#",
+		Type:        "object",
+		Description: c.Summary,
+		Properties:  map[string]*jsonSchema{},
+	}
+
+	for _, v := range c.Fields {
+		if v.Deprecated {
+			continue
+		}
+		fieldSchema, err := fieldAsJSONSchema(v, gConf.S(v.Name))
+		if err != nil {
+			return nil, err
+		}
+		root.Properties[v.Name] = fieldSchema
+	}
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// ComponentsAsJSONSchema renders AsJSONSchema for every entry in components,
+// keyed by name, so that a single document can ship one schema per
+// registered component (e.g. for a `benthos list --format json-schema`
+// output). fullConfigExamples must contain one full configuration example
+// per component, keyed the same way as components. A component that's
+// missing its example or fails to render is recorded in the returned errs
+// map, keyed the same way, and excluded from schemas, rather than failing
+// the whole call — a single component's documentation bug shouldn't block
+// the rest from listing.
+//
+// This only produces the document; wiring an actual `--format json-schema`
+// flag belongs to the CLI command package, which isn't present in this
+// tree.
+func ComponentsAsJSONSchema(components []ComponentSpec, fullConfigExamples map[string]interface{}) (schemas map[string]json.RawMessage, errs map[string]error) {
+	schemas = make(map[string]json.RawMessage, len(components))
+	for _, c := range components {
+		example, ok := fullConfigExamples[c.Name]
+		if !ok {
+			if errs == nil {
+				errs = map[string]error{}
+			}
+			errs[c.Name] = fmt.Errorf("missing full config example for component '%v'", c.Name)
+			continue
+		}
+		schema, err := c.AsJSONSchema(example)
+		if err != nil {
+			if errs == nil {
+				errs = map[string]error{}
+			}
+			errs[c.Name] = fmt.Errorf("failed to render schema for component '%v': %w", c.Name, err)
+			continue
+		}
+		schemas[c.Name] = schema
+	}
+	return schemas, errs
+}
+
+// fieldAsJSONSchema converts a single FieldSpec, and its value within the
+// full config example, into a jsonSchema node. Children are walked
+// recursively into nested properties rather than flattened, unlike the
+// dotted field names used by AsMarkdown.
+func fieldAsJSONSchema(v FieldSpec, gObj *gabs.Container) (*jsonSchema, error) {
+	s := &jsonSchema{
+		Description: v.Description,
+	}
+
+	if len(v.Options) > 0 {
+		s.Enum = v.Options
+	}
+	for _, example := range v.Examples {
+		s.Examples = append(s.Examples, example)
+	}
+	switch v.Interpolation {
+	case FieldInterpolationBatchWide:
+		s.BenthosInterpolation = "batch-wide"
+	case FieldInterpolationIndividual:
+		s.BenthosInterpolation = "individual"
+	}
+
+	if len(v.Children) > 0 {
+		// For an array-of-objects field (e.g. a list of processors),
+		// Children describes the element, not the list itself, matching
+		// AsMarkdown. The config example holds a slice of elements rather
+		// than a single object in that case, so look up each child against
+		// the first element as the representative example instead of gObj
+		// itself.
+		childExampleObj := gObj
+		if v.Type == "array" && gObj != nil {
+			if items := gObj.Children(); len(items) > 0 {
+				childExampleObj = items[0]
+			} else {
+				childExampleObj = nil
+			}
+		}
+
+		objSchema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}}
+		for _, child := range v.Children {
+			if child.Deprecated {
+				continue
+			}
+			var childGObj *gabs.Container
+			if childExampleObj != nil {
+				childGObj = childExampleObj.S(child.Name)
+			}
+			childSchema, err := fieldAsJSONSchema(child, childGObj)
+			if err != nil {
+				return nil, err
+			}
+			objSchema.Properties[child.Name] = childSchema
+		}
+
+		// Wrap the object schema as the array's items rather than
+		// describing the field itself as an object when it's array-kinded.
+		if v.Type == "array" {
+			s.Type = "array"
+			s.Items = objSchema
+			return s, nil
+		}
+
+		s.Type = "object"
+		s.Properties = objSchema.Properties
+		return s, nil
+	}
+
+	if gObj == nil || gObj.Data() == nil {
+		return nil, fmt.Errorf("field '%v' not found in config example", v.Name)
+	}
+	s.Default = gObj.Data()
+
+	fieldType := v.Type
+	if len(fieldType) == 0 {
+		if len(v.Examples) > 0 {
+			fieldType = reflect.TypeOf(v.Examples[0]).Kind().String()
+		} else {
+			fieldType = reflect.TypeOf(gObj.Data()).Kind().String()
+		}
+	}
+	switch fieldType {
+	case "map":
+		fieldType = "object"
+	case "slice":
+		fieldType = "array"
+	case "float64", "int", "int64":
+		fieldType = "number"
+	case "bool":
+		fieldType = "boolean"
+	}
+	s.Type = fieldType
+
+	return s, nil
+}
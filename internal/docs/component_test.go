@@ -0,0 +1,155 @@
+package docs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAsJSONSchemaBasicFields(t *testing.T) {
+	spec := ComponentSpec{
+		Name:    "example",
+		Type:    "input",
+		Summary: "An example component.",
+		Fields: FieldSpecs{
+			{Name: "count", Type: "int", Description: "How many to batch."},
+			{Name: "label", Type: "string", Description: "A label.", Options: []string{"a", "b"}},
+		},
+	}
+	example := map[string]interface{}{
+		"count": 5,
+		"label": "a",
+	}
+
+	raw, err := spec.AsJSONSchema(example)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("failed to unmarshal rendered schema: %v", err)
+	}
+
+	props, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a properties object, got %v", schema["properties"])
+	}
+
+	count, ok := props["count"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a count property, got %v", props["count"])
+	}
+	if count["type"] != "number" {
+		t.Fatalf("expected count's int type to be rendered as 'number', got %v", count["type"])
+	}
+
+	label, ok := props["label"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a label property, got %v", props["label"])
+	}
+	enum, ok := label["enum"].([]interface{})
+	if !ok || len(enum) != 2 {
+		t.Fatalf("expected label's Options to render as a 2-entry enum, got %v", label["enum"])
+	}
+}
+
+func TestFieldAsJSONSchemaArrayOfObjects(t *testing.T) {
+	spec := ComponentSpec{
+		Name: "example",
+		Type: "input",
+		Fields: FieldSpecs{
+			{
+				Name: "processors",
+				Type: "array",
+				Children: FieldSpecs{
+					{Name: "type", Type: "string"},
+				},
+			},
+		},
+	}
+	example := map[string]interface{}{
+		"processors": []interface{}{
+			map[string]interface{}{"type": "bloblang"},
+		},
+	}
+
+	raw, err := spec.AsJSONSchema(example)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("failed to unmarshal rendered schema: %v", err)
+	}
+
+	props := schema["properties"].(map[string]interface{})
+	processors, ok := props["processors"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a processors property, got %v", props["processors"])
+	}
+	if processors["type"] != "array" {
+		t.Fatalf("expected an array-kinded field with Children to render as type 'array', got %v", processors["type"])
+	}
+
+	items, ok := processors["items"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an items schema describing the array element, got %v", processors["items"])
+	}
+	if items["type"] != "object" {
+		t.Fatalf("expected the array's items to be the object schema built from Children, got %v", items["type"])
+	}
+	itemProps, ok := items["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected items to have properties, got %v", items["properties"])
+	}
+	if _, ok := itemProps["type"]; !ok {
+		t.Fatalf("expected the element's 'type' child field to be present, got %v", itemProps)
+	}
+}
+
+func TestComponentsAsJSONSchemaContinuesPastOneBadComponent(t *testing.T) {
+	good := ComponentSpec{
+		Name: "good",
+		Type: "input",
+		Fields: FieldSpecs{
+			{Name: "count", Type: "int"},
+		},
+	}
+	missingExample := ComponentSpec{
+		Name: "missing_example",
+		Type: "input",
+	}
+	badField := ComponentSpec{
+		Name: "bad_field",
+		Type: "input",
+		Fields: FieldSpecs{
+			{Name: "nope", Type: "string"},
+		},
+	}
+
+	schemas, errs := ComponentsAsJSONSchema(
+		[]ComponentSpec{good, missingExample, badField},
+		map[string]interface{}{
+			"good":      map[string]interface{}{"count": 1},
+			"bad_field": map[string]interface{}{"something_else": 1},
+		},
+	)
+
+	if _, ok := schemas["good"]; !ok {
+		t.Fatalf("expected the good component's schema to render despite its siblings failing, got %v", schemas)
+	}
+	if len(schemas) != 1 {
+		t.Fatalf("expected exactly 1 rendered schema, got %d: %v", len(schemas), schemas)
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors recorded, one per bad component, got %d: %v", len(errs), errs)
+	}
+	if errs["missing_example"] == nil {
+		t.Fatalf("expected an error recorded for the component missing its config example")
+	}
+	if errs["bad_field"] == nil {
+		t.Fatalf("expected an error recorded for the component whose field isn't in its config example")
+	}
+}